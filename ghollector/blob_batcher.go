@@ -0,0 +1,197 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	blobsIndexedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "blobs_indexed_total",
+		Help: "Number of blobs successfully written to a BlobSink.",
+	}, []string{"type"})
+
+	blobsFailedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "blobs_failed_total",
+		Help: "Number of blobs that a BlobSink permanently failed to write.",
+	}, []string{"type", "reason"})
+
+	batchLatencySeconds = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "blob_batch_latency_seconds",
+		Help:    "Time spent flushing one batch of blobs to a BlobSink.",
+		Buckets: prometheus.DefBuckets,
+	})
+)
+
+func init() {
+	prometheus.MustRegister(blobsIndexedTotal, blobsFailedTotal, batchLatencySeconds)
+}
+
+// BlobSink accepts a batch of blobs and writes them, e.g. via the Elastic
+// Search bulk API or an S3 multipart upload. BlobBatcher hands blobs over
+// unencoded; each sink encodes them itself. WriteBatch returns the subset of
+// blobs that failed to write so the caller can decide whether to retry
+// them; a non-nil err indicates the whole batch should be considered
+// failed.
+type BlobSink interface {
+	WriteBatch(blobs []*Blob) (failed []*Blob, err error)
+}
+
+// BatcherConfig controls when a BlobBatcher flushes and how it retries
+// partial failures.
+type BatcherConfig struct {
+	// MaxItems is the number of accumulated blobs, per type, that
+	// triggers an immediate flush.
+	MaxItems int
+
+	// MaxLatency is the longest a blob will sit in the batcher before
+	// being flushed, regardless of MaxItems.
+	MaxLatency time.Duration
+
+	// MaxRetries is the number of times a failed blob is resubmitted
+	// before being counted against blobs_failed_total and dropped.
+	MaxRetries int
+
+	// InitialBackoff is the delay before the first retry of a failed
+	// flush; it doubles on each subsequent retry.
+	InitialBackoff time.Duration
+}
+
+// DefaultBatcherConfig holds the default flush and retry thresholds, tuned
+// for bulk throughput.
+var DefaultBatcherConfig = BatcherConfig{
+	MaxItems:       500,
+	MaxLatency:     2 * time.Second,
+	MaxRetries:     5,
+	InitialBackoff: 250 * time.Millisecond,
+}
+
+// BlobBatcher accumulates blobs per type and flushes them to a BlobSink in
+// batches, bounded by either item count or elapsed time. It is the
+// replacement for the one-blob-at-a-time write pattern that bottlenecks
+// throughput when replaying large repos.
+type BlobBatcher struct {
+	sink   BlobSink
+	config BatcherConfig
+
+	in      chan *Blob
+	done    chan struct{}
+	flushWG sync.WaitGroup
+}
+
+// NewBlobBatcher returns a BlobBatcher that flushes to sink according to
+// config. Call Close to flush any remaining blobs and stop the background
+// worker.
+func NewBlobBatcher(sink BlobSink, config BatcherConfig) *BlobBatcher {
+	b := &BlobBatcher{
+		sink:   sink,
+		config: config,
+		in:     make(chan *Blob, config.MaxItems),
+		done:   make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// Add enqueues a blob for batched delivery. It blocks if the internal
+// buffer is full, providing backpressure to the caller.
+func (b *BlobBatcher) Add(blob *Blob) {
+	b.in <- blob
+}
+
+// Close flushes any buffered blobs and waits for every in-flight flush,
+// including ones still retrying with backoff, to finish.
+func (b *BlobBatcher) Close() {
+	close(b.in)
+	<-b.done
+	b.flushWG.Wait()
+}
+
+// run owns byType and the select loop; it only ever decides what to flush
+// and hands each flush off to its own goroutine via flushAsync, so that a
+// sink retrying one type with backoff can't stall draining of b.in or
+// flushing of any other type.
+func (b *BlobBatcher) run() {
+	defer close(b.done)
+
+	byType := make(map[string][]*Blob)
+	ticker := time.NewTicker(b.config.MaxLatency)
+	defer ticker.Stop()
+
+	flushType := func(blobType string) {
+		batch := byType[blobType]
+		if len(batch) == 0 {
+			return
+		}
+		delete(byType, blobType)
+		b.flushAsync(blobType, batch)
+	}
+
+	flushAll := func() {
+		for blobType := range byType {
+			flushType(blobType)
+		}
+	}
+
+	for {
+		select {
+		case blob, ok := <-b.in:
+			if !ok {
+				flushAll()
+				return
+			}
+			byType[blob.Type] = append(byType[blob.Type], blob)
+			if len(byType[blob.Type]) >= b.config.MaxItems {
+				flushType(blob.Type)
+			}
+		case <-ticker.C:
+			flushAll()
+		}
+	}
+}
+
+// flushAsync runs flushWithRetry on its own goroutine, tracked by
+// flushWG, so that its retry backoff sleeps never block run's select
+// loop.
+func (b *BlobBatcher) flushAsync(blobType string, batch []*Blob) {
+	b.flushWG.Add(1)
+	go func() {
+		defer b.flushWG.Done()
+		b.flushWithRetry(blobType, batch)
+	}()
+}
+
+// flushWithRetry writes batch to the sink, retrying only the blobs the
+// sink reports as failed, with exponential backoff, up to MaxRetries.
+func (b *BlobBatcher) flushWithRetry(blobType string, batch []*Blob) {
+	start := time.Now()
+	defer func() {
+		batchLatencySeconds.Observe(time.Since(start).Seconds())
+	}()
+
+	backoff := b.config.InitialBackoff
+	pending := batch
+
+	for attempt := 0; attempt <= b.config.MaxRetries && len(pending) > 0; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		failed, err := b.sink.WriteBatch(pending)
+		if err != nil {
+			blobsFailedTotal.WithLabelValues(blobType, "sink_error").Add(float64(len(pending)))
+			return
+		}
+
+		succeeded := len(pending) - len(failed)
+		blobsIndexedTotal.WithLabelValues(blobType).Add(float64(succeeded))
+		pending = failed
+	}
+
+	if len(pending) > 0 {
+		blobsFailedTotal.WithLabelValues(blobType, "max_retries_exceeded").Add(float64(len(pending)))
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// ContentHash returns a stable SHA-256 hash, hex-encoded, of the blob's
+// Data. encoding/json marshals map[string]interface{} keys in sorted
+// order, so two blobs with the same logical content always hash the same
+// regardless of how their payload happened to be assembled.
+func (b *Blob) ContentHash() (string, error) {
+	canonical, err := b.Data.Encode()
+	if err != nil {
+		return "", fmt.Errorf("content hash: encoding blob %q: %s", b.Id, err)
+	}
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// DedupStore records the content hash last seen for a given (Type, Id)
+// pair, so that repeated snapshots with unchanged content can be skipped
+// instead of appended.
+type DedupStore interface {
+	// Lookup returns the last recorded content hash for blobType/id, and
+	// whether one was found.
+	Lookup(blobType, id string) (hash string, ok bool, err error)
+
+	// Record stores hash as the latest known content hash for
+	// blobType/id, observed at timestamp.
+	Record(blobType, id string, timestamp time.Time, hash string) error
+}
+
+// ShouldWrite reports whether snapshot's content differs from the last
+// hash recorded in store for its (Type, Id), and records the new hash
+// when it does. Callers should skip writing the full document when
+// ShouldWrite returns false.
+func ShouldWrite(store DedupStore, snapshot *Blob) (bool, error) {
+	hash, err := snapshot.ContentHash()
+	if err != nil {
+		return false, err
+	}
+
+	previous, ok, err := store.Lookup(snapshot.Type, snapshot.Id)
+	if err != nil {
+		return false, fmt.Errorf("dedup: looking up %q/%q: %s", snapshot.Type, snapshot.Id, err)
+	}
+	if ok && previous == hash {
+		return false, nil
+	}
+
+	if err := store.Record(snapshot.Type, snapshot.Id, snapshot.Timestamp, hash); err != nil {
+		return false, fmt.Errorf("dedup: recording %q/%q: %s", snapshot.Type, snapshot.Id, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// dedupBucketPrefix namespaces the dedup index buckets so they can share a
+// BoltDB file with a BoltStore without colliding on bucket names.
+const dedupBucketPrefix = "__dedup__"
+
+// dedupTimestampLayout is reused for the small amount of bookkeeping
+// BoltDedupStore keeps alongside the content hash.
+const dedupTimestampLayout = time.RFC3339Nano
+
+// BoltDedupStore is the reference DedupStore implementation. It keeps one
+// bucket per blob type, keyed by id, storing "<contentHash>|<timestamp>".
+type BoltDedupStore struct {
+	db *bolt.DB
+}
+
+// NewBoltDedupStore opens (creating if necessary) a BoltDB file at path to
+// use as a DedupStore.
+func NewBoltDedupStore(path string) (*BoltDedupStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("bolt dedup store: opening %q: %s", path, err)
+	}
+	return &BoltDedupStore{db: db}, nil
+}
+
+// Close releases the underlying BoltDB file handle.
+func (s *BoltDedupStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *BoltDedupStore) Lookup(blobType, id string) (hash string, ok bool, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(dedupBucketPrefix + blobType))
+		if bucket == nil {
+			return nil
+		}
+		value := bucket.Get([]byte(id))
+		if value == nil {
+			return nil
+		}
+		hash, ok = splitDedupValue(value)
+		return nil
+	})
+	return hash, ok, err
+}
+
+func (s *BoltDedupStore) Record(blobType, id string, timestamp time.Time, hash string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(dedupBucketPrefix + blobType))
+		if err != nil {
+			return err
+		}
+		value := []byte(hash + "|" + timestamp.UTC().Format(dedupTimestampLayout))
+		return bucket.Put([]byte(id), value)
+	})
+}
+
+func splitDedupValue(value []byte) (hash string, ok bool) {
+	s := string(value)
+	for i := 0; i < len(s); i++ {
+		if s[i] == '|' {
+			return s[:i], true
+		}
+	}
+	return "", false
+}
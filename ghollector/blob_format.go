@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/bitly/go-simplejson"
+)
+
+// CloudEventsVersion is the CloudEvents spec version implemented by
+// CloudEventsFormat.
+const CloudEventsVersion = "1.0"
+
+// BlobFormat encodes a Blob into the wire representation expected by a
+// downstream consumer (the Elastic Search writer, a transport queue, ...).
+type BlobFormat interface {
+	// Encode serializes b according to the format.
+	Encode(b *Blob) ([]byte, error)
+}
+
+// RawJSONFormat encodes a Blob as its raw Data payload, with no envelope.
+// This is the historical vossibility wire format.
+type RawJSONFormat struct{}
+
+func (RawJSONFormat) Encode(b *Blob) ([]byte, error) {
+	return b.Data.Encode()
+}
+
+// CloudEventsFormat encodes a Blob as a CloudEvents v1.0 JSON envelope, so
+// that vossibility can feed consumers that already speak CloudEvents instead
+// of only ES-shaped documents.
+type CloudEventsFormat struct {
+	// Source is the CloudEvents `source` URI attached to every envelope,
+	// e.g. "https://github.com/<org>/<repo>".
+	Source string
+}
+
+// NewCloudEventsFormat returns a CloudEventsFormat that stamps every event
+// with the given source URI.
+func NewCloudEventsFormat(source string) *CloudEventsFormat {
+	return &CloudEventsFormat{Source: source}
+}
+
+func (f *CloudEventsFormat) Encode(b *Blob) ([]byte, error) {
+	if f.Source == "" {
+		return nil, fmt.Errorf("cloudevents: missing source for blob %q", b.Id)
+	}
+
+	envelope := simplejson.New()
+	envelope.Set("specversion", CloudEventsVersion)
+	envelope.Set("id", b.Id)
+	envelope.Set("type", fmt.Sprintf("com.github.%s", b.Type))
+	envelope.Set("source", f.Source)
+	envelope.Set("time", b.Timestamp.Format("2006-01-02T15:04:05.000Z07:00"))
+	envelope.Set("datacontenttype", "application/json")
+	envelope.Set("data", b.Data)
+
+	return envelope.Encode()
+}
+
+// EncodeAs serializes b using the given format, rather than the default
+// RawJSONFormat used by Encode.
+func (b *Blob) EncodeAs(format BlobFormat) ([]byte, error) {
+	return format.Encode(b)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/bitly/go-simplejson"
+)
+
+// BlobMapping describes how a single GitHub event type is turned into a
+// Blob: which field of the payload identifies the snapshot, which
+// sub-object is the snapshot itself, and which additional attributes get
+// pushed onto the blob via Blob.Push. It is the validated counterpart to
+// the ad-hoc `_`-prefixed keys that pushSpecialAttribute currently accepts
+// on faith.
+type BlobMapping struct {
+	// Type is the blob type this mapping produces, e.g.
+	// "pull_request.opened".
+	Type string `toml:"type"`
+
+	// SnapshotId is the dotted path, relative to SnapshotField, of the
+	// attribute to use as the snapshot id.
+	SnapshotId string `toml:"snapshot_id"`
+
+	// SnapshotField is the dotted path of the sub-object of the payload
+	// to use as the snapshot.
+	SnapshotField string `toml:"snapshot_field"`
+
+	// Push maps arbitrary blob attribute keys to a dotted path in the
+	// source payload that should be copied there.
+	Push map[string]string `toml:"push"`
+}
+
+// BlobMappingConfig is the top-level configuration file format: one
+// BlobMapping per configured event type.
+type BlobMappingConfig struct {
+	Mappings []BlobMapping `toml:"mapping"`
+}
+
+// LoadBlobMappingConfig reads and validates a BlobMappingConfig from a TOML
+// file at path, checking every mapping against sample. It fails fast with
+// an actionable error rather than letting a bad mapping surface later, at
+// ES index time.
+func LoadBlobMappingConfig(path string, sample *simplejson.Json) (*BlobMappingConfig, error) {
+	var config BlobMappingConfig
+	if _, err := toml.DecodeFile(path, &config); err != nil {
+		return nil, fmt.Errorf("blob mapping: parsing %q: %s", path, err)
+	}
+
+	seen := make(map[string]bool)
+	for _, m := range config.Mappings {
+		if seen[m.Type] {
+			return nil, fmt.Errorf("blob mapping: duplicate mapping for type %q", m.Type)
+		}
+		seen[m.Type] = true
+
+		if err := m.Validate(sample); err != nil {
+			return nil, fmt.Errorf("blob mapping %q: %s", m.Type, err)
+		}
+	}
+	return &config, nil
+}
+
+// Validate checks that m is internally consistent and, if sample is
+// non-nil, that SnapshotField and SnapshotId actually resolve against it.
+func (m *BlobMapping) Validate(sample *simplejson.Json) error {
+	if m.Type == "" {
+		return fmt.Errorf("missing required %q field", "type")
+	}
+	if m.SnapshotField == "" {
+		return fmt.Errorf("missing required %q field", "snapshot_field")
+	}
+	if m.SnapshotId == "" {
+		return fmt.Errorf("missing required %q field", "snapshot_id")
+	}
+
+	for key := range m.Push {
+		if strings.HasPrefix(key, "_") {
+			return fmt.Errorf("push target %q must not use the reserved %q prefix", key, "_")
+		}
+	}
+
+	if err := m.checkNoConflictingPushTargets(); err != nil {
+		return err
+	}
+
+	if sample == nil {
+		return nil
+	}
+
+	snapshot, ok := resolvePath(sample, m.SnapshotField)
+	if !ok {
+		return fmt.Errorf("snapshot_field %q does not exist in sample payload", m.SnapshotField)
+	}
+
+	if _, ok := resolvePath(snapshot, m.SnapshotId); !ok {
+		return fmt.Errorf("snapshot_id %q does not exist under snapshot_field %q", m.SnapshotId, m.SnapshotField)
+	}
+
+	return nil
+}
+
+// resolvePath walks a dotted path under json, returning the resolved node
+// and whether every segment of the path existed.
+func resolvePath(json *simplejson.Json, path string) (*simplejson.Json, bool) {
+	node := json
+	for _, part := range strings.Split(path, ".") {
+		var ok bool
+		node, ok = node.CheckGet(part)
+		if !ok {
+			return nil, false
+		}
+	}
+	return node, true
+}
+
+// checkNoConflictingPushTargets ensures two Push entries don't write to the
+// same destination path, which would make the final value
+// order-dependent. Push keys are unique, so a conflict is one target nested
+// under another, e.g. "a" and "a.b".
+func (m *BlobMapping) checkNoConflictingPushTargets() error {
+	for key := range m.Push {
+		for other := range m.Push {
+			if strings.HasPrefix(other, key+".") {
+				return fmt.Errorf("push targets %q and %q both write to %q", key, other, key)
+			}
+		}
+	}
+	return nil
+}
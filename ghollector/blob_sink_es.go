@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/mattbaird/elastigo/lib"
+)
+
+// ESBulkSink is a BlobSink that writes batches through the Elastic Search
+// bulk API, reporting back exactly the documents the response marks as
+// failed so the BlobBatcher only requeues those, instead of the whole
+// batch.
+type ESBulkSink struct {
+	conn  *elastigo.Conn
+	index string
+}
+
+// NewESBulkSink returns an ESBulkSink writing to the given index over conn.
+func NewESBulkSink(conn *elastigo.Conn, index string) *ESBulkSink {
+	return &ESBulkSink{conn: conn, index: index}
+}
+
+// WriteBatch sends blobs as a single synchronous bulk request and returns
+// the subset the response reports as failed. It shares esBulkIndex with
+// ESStore.BulkPut rather than going through BulkIndexer, whose per-item
+// errors surface asynchronously on a channel and can't be mapped back to
+// individual blobs within a single WriteBatch call.
+func (s *ESBulkSink) WriteBatch(blobs []*Blob) ([]*Blob, error) {
+	failed, err := esBulkIndex(s.conn, s.index, blobs)
+	if err != nil {
+		return nil, fmt.Errorf("es bulk sink: %s", err)
+	}
+	return failed, nil
+}
@@ -0,0 +1,23 @@
+package main
+
+import "time"
+
+// BlobStore is the persistence interface for Blob values. The Elastic
+// Search writer was historically the only implementation; BoltStore and
+// S3Store provide alternatives for offline analysis and cheap long-term
+// retention respectively.
+type BlobStore interface {
+	// Put writes a single blob to the store.
+	Put(b *Blob) error
+
+	// BulkPut writes several blobs to the store in one operation.
+	BulkPut(blobs []*Blob) error
+
+	// Get returns the blob of the given type and id, or an error if it
+	// cannot be found.
+	Get(blobType, id string) (*Blob, error)
+
+	// Query returns the blobs of the given type whose Timestamp falls
+	// within [since, until).
+	Query(blobType string, since, until time.Time) ([]*Blob, error)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// timestampIndexBucket is the suffix applied to a blob type bucket name to
+// get its secondary, timestamp-ordered index bucket.
+const timestampIndexBucket = "__by_timestamp"
+
+// indexKeyByIdBucket is the suffix applied to a blob type bucket name to
+// get the bucket that tracks, for each id, the key it currently occupies
+// in the timestamp index. Since the primary bucket overwrites a blob in
+// place on every Put, the index must do the same: without this bucket,
+// re-putting an id under a new Timestamp would leave its old index entry
+// behind, and Query would return that id more than once.
+const indexKeyByIdBucket = "__index_key_by_id"
+
+// timestampKeyLayout produces lexicographically sortable keys so that a
+// Bolt cursor range scan over the index bucket visits blobs in Timestamp
+// order.
+const timestampKeyLayout = "20060102150405.000000000"
+
+// BoltStore is a BlobStore backed by a local BoltDB file. Blobs are stored
+// in one bucket per Blob.Type, keyed by Blob.Id. A secondary bucket per
+// type, named "<type>__by_timestamp", maps a sortable timestamp key to the
+// blob id so that Query can do a range scan instead of a full bucket
+// iteration.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// NewBoltStore opens (creating if necessary) a BoltDB file at path to use
+// as a BlobStore.
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("bolt store: opening %q: %s", path, err)
+	}
+	return &BoltStore{db: db}, nil
+}
+
+// Close releases the underlying BoltDB file handle.
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *BoltStore) Put(b *Blob) error {
+	return s.BulkPut([]*Blob{b})
+}
+
+func (s *BoltStore) BulkPut(blobs []*Blob) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for _, b := range blobs {
+			data, err := b.EncodeStored()
+			if err != nil {
+				return fmt.Errorf("bolt store: encoding blob %q: %s", b.Id, err)
+			}
+
+			bucket, err := tx.CreateBucketIfNotExists([]byte(b.Type))
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put([]byte(b.Id), data); err != nil {
+				return err
+			}
+
+			index, err := tx.CreateBucketIfNotExists([]byte(b.Type + timestampIndexBucket))
+			if err != nil {
+				return err
+			}
+			indexKeyById, err := tx.CreateBucketIfNotExists([]byte(b.Type + indexKeyByIdBucket))
+			if err != nil {
+				return err
+			}
+
+			// A re-put of the same id under a new Timestamp must replace
+			// its old index entry, not add a second one alongside it.
+			if previousKey := indexKeyById.Get([]byte(b.Id)); previousKey != nil {
+				if err := index.Delete(previousKey); err != nil {
+					return err
+				}
+			}
+
+			indexKey := []byte(b.Timestamp.UTC().Format(timestampKeyLayout) + "|" + b.Id)
+			if err := index.Put(indexKey, []byte(b.Id)); err != nil {
+				return err
+			}
+			if err := indexKeyById.Put([]byte(b.Id), indexKey); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Delete removes the blob of the given type and id, along with its
+// timestamp index entry.
+func (s *BoltStore) Delete(blobType, id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(blobType))
+		if bucket == nil {
+			return nil
+		}
+
+		indexKeyById := tx.Bucket([]byte(blobType + indexKeyByIdBucket))
+		if indexKeyById != nil {
+			if indexKey := indexKeyById.Get([]byte(id)); indexKey != nil {
+				if index := tx.Bucket([]byte(blobType + timestampIndexBucket)); index != nil {
+					if err := index.Delete(indexKey); err != nil {
+						return err
+					}
+				}
+				if err := indexKeyById.Delete([]byte(id)); err != nil {
+					return err
+				}
+			}
+		}
+
+		return bucket.Delete([]byte(id))
+	})
+}
+
+func (s *BoltStore) Get(blobType, id string) (*Blob, error) {
+	var blob *Blob
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(blobType))
+		if bucket == nil {
+			return fmt.Errorf("bolt store: no such type %q", blobType)
+		}
+		data := bucket.Get([]byte(id))
+		if data == nil {
+			return fmt.Errorf("bolt store: blob %q/%q not found", blobType, id)
+		}
+		b, err := NewBlobFromStored(blobType, id, data)
+		if err != nil {
+			return err
+		}
+		blob = b
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return blob, nil
+}
+
+func (s *BoltStore) Query(blobType string, since, until time.Time) ([]*Blob, error) {
+	var blobs []*Blob
+	err := s.db.View(func(tx *bolt.Tx) error {
+		index := tx.Bucket([]byte(blobType + timestampIndexBucket))
+		bucket := tx.Bucket([]byte(blobType))
+		if index == nil || bucket == nil {
+			return nil
+		}
+
+		min := []byte(since.UTC().Format(timestampKeyLayout))
+		max := []byte(until.UTC().Format(timestampKeyLayout))
+
+		c := index.Cursor()
+		for k, id := c.Seek(min); k != nil && string(k) < string(max); k, id = c.Next() {
+			data := bucket.Get(id)
+			if data == nil {
+				continue
+			}
+			b, err := NewBlobFromStored(blobType, string(id), data)
+			if err != nil {
+				return err
+			}
+			blobs = append(blobs, b)
+		}
+		return nil
+	})
+	return blobs, err
+}
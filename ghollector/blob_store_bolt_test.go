@@ -0,0 +1,112 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bitly/go-simplejson"
+)
+
+func newTestBoltStore(t *testing.T) (*BoltStore, func()) {
+	f, err := ioutil.TempFile("", "vossibility-blob-store-test")
+	if err != nil {
+		t.Fatalf("creating temp file: %s", err)
+	}
+	path := f.Name()
+	f.Close()
+	os.Remove(path)
+
+	store, err := NewBoltStore(path)
+	if err != nil {
+		t.Fatalf("opening bolt store: %s", err)
+	}
+	return store, func() {
+		store.Close()
+		os.Remove(path)
+	}
+}
+
+func TestBoltStoreRoundTripPreservesTimestamp(t *testing.T) {
+	store, cleanup := newTestBoltStore(t)
+	defer cleanup()
+
+	want := time.Date(2024, time.May, 1, 13, 30, 0, 0, time.UTC)
+	b := NewBlobFromJson("pull_request", "42", simplejson.New())
+	b.Timestamp = want
+
+	if err := store.Put(b); err != nil {
+		t.Fatalf("Put: %s", err)
+	}
+
+	got, err := store.Get("pull_request", "42")
+	if err != nil {
+		t.Fatalf("Get: %s", err)
+	}
+	if !got.Timestamp.Equal(want) {
+		t.Fatalf("Timestamp = %s, want %s", got.Timestamp, want)
+	}
+
+	results, err := store.Query("pull_request", want.Add(-time.Hour), want.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Query: %s", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Query returned %d blobs, want 1", len(results))
+	}
+	if !results[0].Timestamp.Equal(want) {
+		t.Fatalf("Query Timestamp = %s, want %s", results[0].Timestamp, want)
+	}
+}
+
+func TestBoltStorePruneKeepsDailyBuckets(t *testing.T) {
+	store, cleanup := newTestBoltStore(t)
+	defer cleanup()
+
+	base := time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC)
+	const days = 5
+	const perDay = 3
+
+	for day := 0; day < days; day++ {
+		for i := 0; i < perDay; i++ {
+			ts := base.AddDate(0, 0, day).Add(time.Duration(i) * time.Hour)
+			b := NewBlobFromJson("pull_request", idForDayHour(day, i), simplejson.New())
+			b.Timestamp = ts
+			if err := store.Put(b); err != nil {
+				t.Fatalf("Put day %d hour %d: %s", day, i, err)
+			}
+		}
+	}
+
+	snapshots, err := store.Query("pull_request", base.Add(-time.Hour), base.AddDate(0, 0, days+1))
+	if err != nil {
+		t.Fatalf("Query: %s", err)
+	}
+	if len(snapshots) != days*perDay {
+		t.Fatalf("Query returned %d snapshots, want %d (duplicate index entries?)", len(snapshots), days*perDay)
+	}
+
+	keep, remove, _ := ApplyPolicy(snapshots, SnapshotPolicy{KeepDaily: 3})
+	if len(keep) != 3 {
+		t.Fatalf("kept %d snapshots, want 3 (one per day for the 3 most recent days)", len(keep))
+	}
+	if len(remove) != len(snapshots)-3 {
+		t.Fatalf("removed %d snapshots, want %d", len(remove), len(snapshots)-3)
+	}
+
+	wantDays := map[string]bool{"2024-05-05": true, "2024-05-04": true, "2024-05-03": true}
+	for _, b := range keep {
+		day := b.Timestamp.Format("2006-01-02")
+		if !wantDays[day] {
+			t.Errorf("kept unexpected day %s", day)
+		}
+		if b.Timestamp.Hour() != perDay-1 {
+			t.Errorf("kept snapshot at hour %d for day %s, want the newest (hour %d)", b.Timestamp.Hour(), day, perDay-1)
+		}
+	}
+}
+
+func idForDayHour(day, hour int) string {
+	return time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, day).Add(time.Duration(hour) * time.Hour).Format("20060102-15")
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bitly/go-simplejson"
+)
+
+// storedTimestampLayout is the wire format used for the envelope's
+// timestamp field; RFC3339Nano round-trips time.Time without losing
+// precision.
+const storedTimestampLayout = time.RFC3339Nano
+
+// EncodeStored serializes b as a small envelope of {timestamp, data}
+// rather than Data alone, so that a BlobStore can restore Timestamp when
+// reading a blob back. ESStore and ESBulkSink index this envelope too.
+// Plain Encode stays payload-only; RawJSONFormat and S3Store's NDJSON
+// archive use that payload-only form, which does not carry Timestamp.
+func (b *Blob) EncodeStored() ([]byte, error) {
+	return b.envelope().Encode()
+}
+
+func (b *Blob) envelope() *simplejson.Json {
+	envelope := simplejson.New()
+	envelope.Set("timestamp", b.Timestamp.UTC().Format(storedTimestampLayout))
+	envelope.Set("data", b.Data)
+	return envelope
+}
+
+// NewBlobFromStored reconstructs a Blob from the envelope written by
+// EncodeStored, restoring its original Timestamp instead of defaulting it
+// to the current time the way NewBlobFromPayload does.
+func NewBlobFromStored(blobType, id string, payload []byte) (*Blob, error) {
+	envelope, err := simplejson.NewJson(payload)
+	if err != nil {
+		return nil, fmt.Errorf("stored blob: parsing envelope: %s", err)
+	}
+
+	rawTimestamp, err := envelope.Get("timestamp").String()
+	if err != nil {
+		return nil, fmt.Errorf("stored blob: missing or invalid %q field", "timestamp")
+	}
+	timestamp, err := time.Parse(storedTimestampLayout, rawTimestamp)
+	if err != nil {
+		return nil, fmt.Errorf("stored blob: parsing %q: %s", "timestamp", err)
+	}
+
+	data, ok := envelope.CheckGet("data")
+	if !ok {
+		return nil, fmt.Errorf("stored blob: missing %q field", "data")
+	}
+
+	b := NewBlobFromJson(blobType, id, data)
+	b.Timestamp = timestamp
+	return b, nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/bitly/go-simplejson"
+	"github.com/mattbaird/elastigo/lib"
+)
+
+// ESStore is the original Elastic Search write path, reshaped to satisfy
+// BlobStore so it can be swapped for BoltStore or S3Store without touching
+// callers.
+type ESStore struct {
+	conn  *elastigo.Conn
+	index string
+}
+
+// NewESStore returns an ESStore writing to the given Elastic Search index
+// over conn.
+func NewESStore(conn *elastigo.Conn, index string) *ESStore {
+	return &ESStore{conn: conn, index: index}
+}
+
+func (s *ESStore) Put(b *Blob) error {
+	_, err := s.conn.Index(s.index, b.Type, b.Id, nil, b.envelope())
+	if err != nil {
+		return fmt.Errorf("es store: indexing %q/%q: %s", b.Type, b.Id, err)
+	}
+	return nil
+}
+
+// BulkPut indexes blobs synchronously through the Elastic Search bulk API
+// and reports an error if any document failed to index. BulkIndexer
+// queues documents onto a worker goroutine and reports failures, if at
+// all, on a channel that nothing here drained, so it could return a nil
+// error while silently dropping documents; esBulkIndex sends the request
+// and parses its response directly instead.
+func (s *ESStore) BulkPut(blobs []*Blob) error {
+	failed, err := esBulkIndex(s.conn, s.index, blobs)
+	if err != nil {
+		return fmt.Errorf("es store: bulk indexing: %s", err)
+	}
+	if len(failed) > 0 {
+		ids := make([]string, len(failed))
+		for i, b := range failed {
+			ids[i] = fmt.Sprintf("%s/%s", b.Type, b.Id)
+		}
+		return fmt.Errorf("es store: bulk indexing failed for %d document(s): %v", len(failed), ids)
+	}
+	return nil
+}
+
+func (s *ESStore) Get(blobType, id string) (*Blob, error) {
+	res, err := s.conn.Get(s.index, blobType, id, nil)
+	if err != nil {
+		return nil, fmt.Errorf("es store: getting %q/%q: %s", blobType, id, err)
+	}
+	return NewBlobFromStored(blobType, id, *res.Source)
+}
+
+func (s *ESStore) Query(blobType string, since, until time.Time) ([]*Blob, error) {
+	return nil, fmt.Errorf("es store: Query is not implemented, use the Elastic Search API directly")
+}
+
+// esBulkIndex sends blobs to the Elastic Search `_bulk` endpoint in a
+// single synchronous request and returns the subset that the response
+// reports as failed. It is used directly by ESStore.BulkPut and by
+// ESBulkSink, which both need a real per-document error list rather than
+// BulkIndexer's fire-and-forget error channel.
+func esBulkIndex(conn *elastigo.Conn, index string, blobs []*Blob) ([]*Blob, error) {
+	var body bytes.Buffer
+	byId := make(map[string]*Blob, len(blobs))
+
+	for _, b := range blobs {
+		action, err := json.Marshal(map[string]interface{}{
+			"index": map[string]string{
+				"_index": index,
+				"_type":  b.Type,
+				"_id":    b.Id,
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("encoding bulk action for %q/%q: %s", b.Type, b.Id, err)
+		}
+		data, err := b.EncodeStored()
+		if err != nil {
+			return nil, fmt.Errorf("encoding blob %q/%q: %s", b.Type, b.Id, err)
+		}
+
+		body.Write(action)
+		body.WriteByte('\n')
+		body.Write(data)
+		body.WriteByte('\n')
+		byId[b.Id] = b
+	}
+
+	raw, err := conn.DoCommand("POST", "/_bulk", nil, body.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("bulk request: %s", err)
+	}
+
+	resp, err := simplejson.NewJson(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parsing bulk response: %s", err)
+	}
+
+	var failed []*Blob
+	for _, item := range resp.Get("items").MustArray() {
+		itemMap, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, result := range itemMap {
+			resultMap, ok := result.(map[string]interface{})
+			if !ok || resultMap["error"] == nil {
+				continue
+			}
+			id, _ := resultMap["_id"].(string)
+			if b, ok := byId[id]; ok {
+				failed = append(failed, b)
+			}
+		}
+	}
+	return failed, nil
+}
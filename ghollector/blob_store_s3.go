@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// S3Store is a write-mostly BlobStore intended for cheap, long-term
+// archival rather than point lookups. Blobs handed to BulkPut are batched
+// into a single gzipped NDJSON object per call, partitioned by
+// "<type>/YYYY/MM/DD/<timestamp>.ndjson.gz". Get and Query are not
+// supported; pull the archive down and replay it offline instead.
+type S3Store struct {
+	svc    *s3.S3
+	bucket string
+}
+
+// NewS3Store returns an S3Store that archives blobs into the given bucket.
+func NewS3Store(svc *s3.S3, bucket string) *S3Store {
+	return &S3Store{svc: svc, bucket: bucket}
+}
+
+func (s *S3Store) Put(b *Blob) error {
+	return s.BulkPut([]*Blob{b})
+}
+
+// BulkPut gzips blobs as newline-delimited JSON and uploads them as a
+// single object per call. Blobs of differing Type are partitioned into one
+// object per type.
+func (s *S3Store) BulkPut(blobs []*Blob) error {
+	byType := make(map[string][]*Blob)
+	for _, b := range blobs {
+		byType[b.Type] = append(byType[b.Type], b)
+	}
+
+	for blobType, typeBlobs := range byType {
+		body, err := gzipNDJSON(typeBlobs)
+		if err != nil {
+			return fmt.Errorf("s3 store: encoding %q batch: %s", blobType, err)
+		}
+
+		key := s3ArchiveKey(blobType, typeBlobs[0].Timestamp)
+		_, err = s.svc.PutObject(&s3.PutObjectInput{
+			Bucket:          aws.String(s.bucket),
+			Key:             aws.String(key),
+			Body:            bytes.NewReader(body),
+			ContentType:     aws.String("application/x-ndjson"),
+			ContentEncoding: aws.String("gzip"),
+		})
+		if err != nil {
+			return fmt.Errorf("s3 store: uploading %q: %s", key, err)
+		}
+	}
+	return nil
+}
+
+func (s *S3Store) Get(blobType, id string) (*Blob, error) {
+	return nil, fmt.Errorf("s3 store: Get is not supported, the archive is write-only")
+}
+
+func (s *S3Store) Query(blobType string, since, until time.Time) ([]*Blob, error) {
+	return nil, fmt.Errorf("s3 store: Query is not supported, the archive is write-only")
+}
+
+// s3ArchiveKey returns the partitioned object key for a batch of the given
+// type created at t, e.g. "pull_request/2024/05/01/143000.000000000.ndjson.gz".
+func s3ArchiveKey(blobType string, t time.Time) string {
+	t = t.UTC()
+	return fmt.Sprintf("%s/%04d/%02d/%02d/%s.ndjson.gz",
+		blobType, t.Year(), t.Month(), t.Day(), t.Format("150405.000000000"))
+}
+
+func gzipNDJSON(blobs []*Blob) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	for _, b := range blobs {
+		data, err := b.Encode()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if _, err := w.Write([]byte("\n")); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
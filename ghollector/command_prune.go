@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/codegangsta/cli"
+)
+
+// NewPruneCommand returns the `vossibility prune` command, which applies a
+// SnapshotPolicy against the configured BlobStore and removes whatever
+// ApplyPolicy decides is no longer worth keeping.
+func NewPruneCommand() cli.Command {
+	return cli.Command{
+		Name:  "prune",
+		Usage: "remove snapshots that fall outside the configured retention policy",
+		Flags: []cli.Flag{
+			cli.StringFlag{Name: "type", Usage: "blob type to prune"},
+			cli.IntFlag{Name: "keep-last"},
+			cli.IntFlag{Name: "keep-hourly"},
+			cli.IntFlag{Name: "keep-daily"},
+			cli.IntFlag{Name: "keep-weekly"},
+			cli.IntFlag{Name: "keep-monthly"},
+			cli.IntFlag{Name: "keep-yearly"},
+			cli.BoolFlag{Name: "dry-run", Usage: "print what would be removed without deleting anything"},
+		},
+		Action: runPrune,
+	}
+}
+
+func runPrune(c *cli.Context) {
+	blobType := c.String("type")
+	if blobType == "" {
+		log.Fatal("prune: missing required --type flag")
+	}
+
+	store, err := NewBoltStore(c.GlobalString("store"))
+	if err != nil {
+		log.Fatalf("prune: %s", err)
+	}
+
+	policy := SnapshotPolicy{
+		KeepLast:    c.Int("keep-last"),
+		KeepHourly:  c.Int("keep-hourly"),
+		KeepDaily:   c.Int("keep-daily"),
+		KeepWeekly:  c.Int("keep-weekly"),
+		KeepMonthly: c.Int("keep-monthly"),
+		KeepYearly:  c.Int("keep-yearly"),
+	}
+
+	if policy.IsEmpty() && !c.Bool("dry-run") {
+		log.Fatal("prune: refusing to run an empty policy (no --keep-* flags set), which would remove every snapshot; pass --dry-run to see what that implies or set at least one --keep-* flag")
+	}
+
+	snapshots, err := store.Query(blobType, time.Time{}, time.Now())
+	if err != nil {
+		log.Fatalf("prune: querying %q: %s", blobType, err)
+	}
+
+	keep, remove, reasons := ApplyPolicy(snapshots, policy)
+	for i, b := range keep {
+		log.Printf("prune: keeping %s/%s (%s)", b.Type, b.Id, reasons[i])
+	}
+
+	if c.Bool("dry-run") {
+		for _, b := range remove {
+			log.Printf("prune: would remove %s/%s", b.Type, b.Id)
+		}
+		return
+	}
+
+	for _, b := range remove {
+		if err := removeBlob(store, b); err != nil {
+			log.Fatalf("prune: removing %s/%s: %s", b.Type, b.Id, err)
+		}
+	}
+	log.Printf("prune: removed %d of %d snapshots for type %q", len(remove), len(snapshots), blobType)
+}
+
+// removeBlob deletes b from store, if the store supports deletion.
+func removeBlob(store BlobStore, b *Blob) error {
+	deleter, ok := store.(interface {
+		Delete(blobType, id string) error
+	})
+	if !ok {
+		return fmt.Errorf("store does not support deletion")
+	}
+	return deleter.Delete(b.Type, b.Id)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// SnapshotPolicy describes how many snapshots to retain at each retention
+// granularity, modeled after restic's expire policy. A zero value for a
+// Keep* field means "keep none at that granularity"; KeepLast <= 0 keeps
+// nothing by recency.
+type SnapshotPolicy struct {
+	// KeepLast keeps the N most recent snapshots, regardless of bucket.
+	KeepLast int
+
+	// KeepHourly keeps the newest snapshot for each of the last N hours
+	// that have at least one snapshot.
+	KeepHourly int
+
+	// KeepDaily keeps the newest snapshot for each of the last N days.
+	KeepDaily int
+
+	// KeepWeekly keeps the newest snapshot for each of the last N weeks.
+	KeepWeekly int
+
+	// KeepMonthly keeps the newest snapshot for each of the last N months.
+	KeepMonthly int
+
+	// KeepYearly keeps the newest snapshot for each of the last N years.
+	KeepYearly int
+
+	// KeepTags always keeps snapshots whose Type matches one of these
+	// tags, regardless of age.
+	KeepTags []string
+}
+
+// IsEmpty reports whether p keeps nothing at all: no Keep* count is
+// positive and no KeepTags are set. Applying an empty policy removes
+// every snapshot, which restic (the model for this policy) refuses to do
+// without an explicit override; callers should do the same.
+func (p SnapshotPolicy) IsEmpty() bool {
+	return p.KeepLast <= 0 &&
+		p.KeepHourly <= 0 &&
+		p.KeepDaily <= 0 &&
+		p.KeepWeekly <= 0 &&
+		p.KeepMonthly <= 0 &&
+		p.KeepYearly <= 0 &&
+		len(p.KeepTags) == 0
+}
+
+// bucketRule pairs a bucket-key function with the number of buckets to
+// keep from it.
+type bucketRule struct {
+	name   string
+	keep   int
+	bucket func(snapshot *Blob) string
+}
+
+// ApplyPolicy groups snapshots (as produced by Blob.Snapshot) by the
+// granularities configured in p, keeps the newest snapshot in each bucket
+// up to the requested count, unions those keep-sets together with
+// KeepLast and KeepTags, and returns the kept and removed snapshots along
+// with a parallel reasons slice explaining why each kept snapshot
+// survived.
+func ApplyPolicy(snapshots []*Blob, p SnapshotPolicy) (keep, remove []*Blob, reasons []string) {
+	ordered := make([]*Blob, len(snapshots))
+	copy(ordered, snapshots)
+	sort.Slice(ordered, func(i, j int) bool {
+		return ordered[i].Timestamp.After(ordered[j].Timestamp)
+	})
+
+	kept := make(map[*Blob]bool)
+	reasonFor := make(map[*Blob]string)
+
+	keepWithReason := func(b *Blob, reason string) {
+		if !kept[b] {
+			kept[b] = true
+			reasonFor[b] = reason
+		}
+	}
+
+	for i, b := range ordered {
+		if i < p.KeepLast {
+			keepWithReason(b, fmt.Sprintf("last %d", p.KeepLast))
+		}
+		for _, tag := range p.KeepTags {
+			if b.Type == tag {
+				keepWithReason(b, fmt.Sprintf("tag %q", tag))
+			}
+		}
+	}
+
+	rules := []bucketRule{
+		{"hourly", p.KeepHourly, func(b *Blob) string { return b.Timestamp.Format("2006-01-02T15") }},
+		{"daily", p.KeepDaily, func(b *Blob) string { return b.Timestamp.Format("2006-01-02") }},
+		{"weekly", p.KeepWeekly, func(b *Blob) string {
+			year, week := b.Timestamp.ISOWeek()
+			return fmt.Sprintf("%04d-W%02d", year, week)
+		}},
+		{"monthly", p.KeepMonthly, func(b *Blob) string { return b.Timestamp.Format("2006-01") }},
+		{"yearly", p.KeepYearly, func(b *Blob) string { return b.Timestamp.Format("2006") }},
+	}
+
+	for _, rule := range rules {
+		if rule.keep <= 0 {
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, b := range ordered {
+			if len(seen) >= rule.keep {
+				break
+			}
+			key := rule.bucket(b)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			keepWithReason(b, fmt.Sprintf("%s %s", rule.name, key))
+		}
+	}
+
+	for _, b := range ordered {
+		if kept[b] {
+			keep = append(keep, b)
+			reasons = append(reasons, reasonFor[b])
+		} else {
+			remove = append(remove, b)
+		}
+	}
+	return keep, remove, reasons
+}